@@ -6,12 +6,17 @@ import (
 	"strconv"
 
 	"github.com/joho/godotenv"
+
+	"had-service/internal/mail"
+	"had-service/internal/user/providers"
 )
 
 // Config holds all configuration for our application
 type EnvConfig struct {
 	// jwt settings
 	JwtScretKey string
+	JwtIssuer   string
+	JwtAudience string
 
 	// Database settings
 	DBHost     string
@@ -24,6 +29,14 @@ type EnvConfig struct {
 	AppEnv   string
 	LogLevel string
 	Port     int
+
+	// OAuth2/OIDC social login settings, one config block per provider
+	GoogleOAuth providers.OIDCConfig
+	GitHubOAuth providers.OIDCConfig
+	GenericOIDC providers.OIDCConfig
+
+	// SMTP settings for the email-OTP subsystem
+	SMTP mail.SMTPConfig
 }
 
 // Load returns a config struct populated from environment variables
@@ -35,10 +48,13 @@ func EnvLoad() *EnvConfig {
 
 	port, _ := strconv.Atoi(getEnv("PORT", "9090"))
 	dbPort, _ := strconv.Atoi(getEnv("DB_PORT", "5432"))
+	smtpPort, _ := strconv.Atoi(getEnv("SMTP_PORT", "587"))
 
 	return &EnvConfig{
 		// API settings
 		JwtScretKey: getEnv("JWT_SCRET_KEY", ""),
+		JwtIssuer:   getEnv("JWT_ISSUER", "had-service"),
+		JwtAudience: getEnv("JWT_AUDIENCE", "had-service"),
 
 		// Database settings
 		DBHost:     getEnv("DB_HOST", "localhost"),
@@ -51,6 +67,33 @@ func EnvLoad() *EnvConfig {
 		AppEnv:   getEnv("APP_ENV", "development"),
 		LogLevel: getEnv("LOG_LEVEL", "info"),
 		Port:     port,
+
+		// OAuth2/OIDC social login settings
+		GoogleOAuth: providers.OIDCConfig{
+			ClientID:     getEnv("GOOGLE_OAUTH_CLIENT_ID", ""),
+			ClientSecret: getEnv("GOOGLE_OAUTH_CLIENT_SECRET", ""),
+			RedirectURI:  getEnv("GOOGLE_OAUTH_REDIRECT_URI", ""),
+		},
+		GitHubOAuth: providers.OIDCConfig{
+			ClientID:     getEnv("GITHUB_OAUTH_CLIENT_ID", ""),
+			ClientSecret: getEnv("GITHUB_OAUTH_CLIENT_SECRET", ""),
+			RedirectURI:  getEnv("GITHUB_OAUTH_REDIRECT_URI", ""),
+		},
+		GenericOIDC: providers.OIDCConfig{
+			IssuerURL:    getEnv("OIDC_ISSUER_URL", ""),
+			ClientID:     getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURI:  getEnv("OIDC_REDIRECT_URI", ""),
+		},
+
+		// SMTP settings
+		SMTP: mail.SMTPConfig{
+			Host:     getEnv("SMTP_HOST", "localhost"),
+			Port:     smtpPort,
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("SMTP_FROM", "no-reply@localhost"),
+		},
 	}
 }
 
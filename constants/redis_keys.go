@@ -5,6 +5,34 @@ const (
 	// ActiveUsersRK is the Redis key for the set of all active users
 	ActiveUsersRK = "active_users"
 
-	// UserSessionRK is the prefix for storing session data
-	UserSessionRK = "session:%s"
+	// UserSessionRK stores one session's hash, keyed by owning user and
+	// session ID: session:{userID}:{sessionID}.
+	UserSessionRK = "session:%s:%s"
+
+	// UserSessionIndexRK is the set of a user's live session IDs:
+	// sessions:{userID}.
+	UserSessionIndexRK = "sessions:%s"
+
+	// SessionFamilyRK is the set of every session ID ever issued under a
+	// refresh-token rotation family, used to revoke the family in bulk
+	// when reuse of a retired refresh token is detected:
+	// session_family:{familyID}.
+	SessionFamilyRK = "session_family:%s"
+
+	// SessionFamilyOwnerRK maps a (possibly retired) session ID to its
+	// rotation family ID and owning user, so a reused refresh token can
+	// still be traced back to the family to revoke even after its
+	// session has been rotated away: session_family_owner:{sessionID}.
+	SessionFamilyOwnerRK = "session_family_owner:%s"
+
+	// MFAFailureRK is the prefix for the per-user TOTP verification
+	// failure counter used to rate-limit brute-force attempts.
+	MFAFailureRK = "mfa:fail:%s"
+
+	// OTPCodeRK stores the hashed email-OTP code for a (purpose, userID)
+	// pair: otp:{purpose}:{userID}.
+	OTPCodeRK = "otp:%s:%s"
+
+	// OTPAttemptsRK tracks verification attempts for the same pair.
+	OTPAttemptsRK = "otp:%s:%s:attempts"
 )
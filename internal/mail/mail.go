@@ -0,0 +1,58 @@
+// Package mail sends templated transactional email (currently just OTP
+// codes) through a pluggable Sender, so the SMTP transport and the
+// template bodies can each be swapped independently.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+)
+
+// Template names a registered email body template.
+type Template string
+
+const TemplateOTP Template = "otp"
+
+// OTPTemplateData is the data passed to TemplateOTP.
+type OTPTemplateData struct {
+	Code    string
+	Purpose string
+	TTL     string
+}
+
+// Sender delivers a rendered template to an address.
+type Sender interface {
+	Send(ctx context.Context, to string, tmpl Template, data any) error
+}
+
+// TemplateSet maps template names to their parsed body, so callers can
+// register or override templates without touching sender code.
+type TemplateSet map[Template]*template.Template
+
+// DefaultTemplates returns the built-in templates shipped with the service.
+func DefaultTemplates() TemplateSet {
+	return TemplateSet{
+		TemplateOTP: template.Must(template.New(string(TemplateOTP)).Parse(otpEmailBody)),
+	}
+}
+
+const otpEmailBody = `<p>Your verification code is <strong>{{.Code}}</strong>.</p>
+<p>It expires in {{.TTL}} and was requested for: {{.Purpose}}.</p>
+<p>If you didn't request this, you can safely ignore this email.</p>`
+
+// render executes the named template from set against data.
+func render(set TemplateSet, tmpl Template, data any) (string, error) {
+	t, ok := set[tmpl]
+	if !ok {
+		return "", fmt.Errorf("mail: unknown template %q", tmpl)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("mail: rendering template %q: %w", tmpl, err)
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,54 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+)
+
+// SMTPConfig holds the connection details for an outbound SMTP relay.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPSender sends email via a configured SMTP relay.
+type SMTPSender struct {
+	cfg       SMTPConfig
+	templates TemplateSet
+}
+
+// NewSMTPSender builds a Sender backed by net/smtp. A nil templates set
+// falls back to DefaultTemplates().
+func NewSMTPSender(cfg SMTPConfig, templates TemplateSet) *SMTPSender {
+	if templates == nil {
+		templates = DefaultTemplates()
+	}
+	return &SMTPSender{cfg: cfg, templates: templates}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, to string, tmpl Template, data any) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	body, err := render(s.templates, tmpl, data)
+	if err != nil {
+		return err
+	}
+
+	addr := fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port)
+	auth := smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+
+	msg := []byte(fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: Your verification code\r\nContent-Type: text/html; charset=\"UTF-8\"\r\n\r\n%s",
+		s.cfg.From, to, body,
+	))
+
+	return smtp.SendMail(addr, auth, s.cfg.From, []string{to}, msg)
+}
@@ -2,24 +2,59 @@ package user
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
-	"had-service/config"
+	"fmt"
+	"had-service/internal/mail"
+	"had-service/internal/user/providers"
+	"had-service/internal/user/role"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
 type AuthService interface {
 
 	// Auth
 	Login(ctx context.Context, req LoginRequest) (*LoginResponse, error)
-	Logout(ctx context.Context, sessionID string) error
+	Logout(ctx context.Context, userID, sessionID string) error
+
+	// Social login
+	OAuthAuthURL(providerName, state string) (string, error)
+	LoginWithProvider(ctx context.Context, providerName, code, state string) (*LoginResponse, error)
+
+	// Two-factor authentication (TOTP)
+	EnrollTOTP(ctx context.Context, userID string) (secret string, otpauthURL string, err error)
+	ConfirmTOTP(ctx context.Context, userID string, code string) (recoveryCodes []string, err error)
+	DisableTOTP(ctx context.Context, userID string, code string) error
+	VerifyMFA(ctx context.Context, pendingToken string, code string) (*LoginResponse, error)
+
+	// Email OTP (password reset, email verification, reauthentication)
+	SendEmailOTP(ctx context.Context, email string, purpose OTPPurpose) error
+	VerifyEmailOTP(ctx context.Context, email string, purpose OTPPurpose, code string) error
+
+	// Session management (device listing, remote logout)
+	ListSessions(ctx context.Context, userID string) ([]UserSession, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+
+	// RBAC administration
+	UpdateUserRole(ctx context.Context, userID string, r role.Role) error
+	UpdateUserScopes(ctx context.Context, userID string, scopes []string) error
+
+	// Personal access tokens
+	CreateAccessToken(ctx context.Context, userID string, req CreateAccessTokenRequest) (*CreateAccessTokenResponse, error)
+	ListAccessTokens(ctx context.Context, userID string) ([]AccessToken, error)
+	RevokeAccessToken(ctx context.Context, userID, tokenID string) error
 
 	// Auth helpers
-	ValidateToken(tokenString string) (string, error)
-	RefreshSession(ctx context.Context, sessionID string) (*LoginResponse, error)
+	ValidateToken(ctx context.Context, tokenString string) (*Claims, error)
+	RefreshSession(ctx context.Context, refreshToken string) (*LoginResponse, error)
 	RequestPasswordReset(ctx context.Context, req PasswordResetRequest) error
-	ResetPassword(ctx context.Context, token, newPassword string) error
+	ResetPassword(ctx context.Context, email, code, newPassword string) error
 }
 
 type AuthServiceConfig struct {
@@ -27,6 +62,24 @@ type AuthServiceConfig struct {
 	TokenExpiration  time.Duration
 	UploadDir        string
 	DefaultAvatarURL string
+
+	// RefreshTokenTTL bounds how long a session's refresh token stays
+	// redeemable before the user must log in again.
+	RefreshTokenTTL time.Duration
+
+	// Issuer and Audience populate the access token's iss/aud claims.
+	Issuer   string
+	Audience string
+
+	// OAuthProviders maps a provider name ("google", "github", "oidc") to
+	// the LoginProvider that handles it. Providers absent from this map
+	// are rejected by OAuthAuthURL/LoginWithProvider.
+	OAuthProviders map[string]providers.LoginProvider
+
+	// OTPStore and Mailer back the email-OTP flows (password reset,
+	// email verification, reauthentication).
+	OTPStore OTPStore
+	Mailer   mail.Sender
 }
 
 // ServiceImpl implements the Service interface
@@ -42,6 +95,72 @@ func NewAuthService(repo UserRepository, config AuthServiceConfig) AuthService {
 	}
 }
 
+// generateToken signs a short-lived access token for user, carrying their
+// roles and scopes alongside the RFC 7519 registered claims.
+func (s *AuthServiceImpl) generateToken(user *User) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		Roles:  []role.Role{user.Role},
+		Scopes: user.Scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.ID,
+			Issuer:    s.authConfig.Issuer,
+			Audience:  jwt.ClaimStrings{s.authConfig.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.authConfig.TokenExpiration)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(s.authConfig.JWTSecret))
+}
+
+// mfaPendingTTL bounds how long a user has to complete the TOTP step
+// after a successful password check before having to log in again.
+const mfaPendingTTL = 5 * time.Minute
+
+// generateMFAPendingToken signs a short-lived token identifying the user
+// as having passed the password step but still owing a TOTP code.
+func (s *AuthServiceImpl) generateMFAPendingToken(userID string) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":         userID,
+		"mfa_pending": true,
+		"iat":         time.Now().Unix(),
+		"exp":         time.Now().Add(mfaPendingTTL).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(s.authConfig.JWTSecret))
+}
+
+// parseMFAPendingToken validates a token produced by generateMFAPendingToken
+// and returns the pending user's ID.
+func (s *AuthServiceImpl) parseMFAPendingToken(tokenString string) (string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(s.authConfig.JWTSecret), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return "", errors.New("invalid mfa pending token")
+	}
+	if pending, _ := claims["mfa_pending"].(bool); !pending {
+		return "", errors.New("token is not an mfa pending token")
+	}
+
+	userID, ok := claims["sub"].(string)
+	if !ok || userID == "" {
+		return "", errors.New("invalid user ID in mfa pending token")
+	}
+
+	return userID, nil
+}
+
 func (s *AuthServiceImpl) Login(ctx context.Context, req LoginRequest) (*LoginResponse, error) {
 	// Get user by email
 	user, err := s.userRepo.GetByEmail(ctx, req.Email)
@@ -54,89 +173,398 @@ func (s *AuthServiceImpl) Login(ctx context.Context, req LoginRequest) (*LoginRe
 		return nil, errors.New("invalid password")
 	}
 
-	// Generate token
-	token, err := s.generateToken(user.ID)
+	// If 2FA is enabled, short-circuit with a pending token instead of
+	// issuing a full session; the caller must call VerifyMFA next.
+	if user.TOTPEnabled {
+		pendingToken, err := s.generateMFAPendingToken(user.ID)
+		if err != nil {
+			return nil, errors.New("failed to generate mfa pending token")
+		}
+		return &LoginResponse{
+			MFARequired:  true,
+			PendingToken: pendingToken,
+		}, nil
+	}
+
+	return s.issueLoginResponse(ctx, user)
+}
+
+func (s *AuthServiceImpl) Logout(ctx context.Context, userID, sessionID string) error {
+	return s.userRepo.RevokeSession(ctx, userID, sessionID)
+}
+
+// ListSessions returns every live session (device) for userID.
+func (s *AuthServiceImpl) ListSessions(ctx context.Context, userID string) ([]UserSession, error) {
+	return s.userRepo.ListSessions(ctx, userID)
+}
+
+// RevokeSession logs a single device out remotely.
+func (s *AuthServiceImpl) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	return s.userRepo.RevokeSession(ctx, userID, sessionID)
+}
+
+// UpdateUserRole changes a user's RBAC role. It does not itself check that
+// the caller is authorized; that's RequireRole's job.
+func (s *AuthServiceImpl) UpdateUserRole(ctx context.Context, userID string, r role.Role) error {
+	if !r.Valid() {
+		return fmt.Errorf("unknown role: %s", r)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	user.Role = r
+	return s.userRepo.Update(ctx, user)
+}
+
+// UpdateUserScopes replaces a user's scope grants wholesale.
+func (s *AuthServiceImpl) UpdateUserScopes(ctx context.Context, userID string, scopes []string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	user.Scopes = scopes
+	return s.userRepo.Update(ctx, user)
+}
+
+// ValidateToken authenticates a bearer token, whether it's a signed JWT
+// access token or an opaque personal access token (prefixed patPrefix),
+// and returns the resulting claims so callers can enforce RBAC without a
+// second lookup.
+func (s *AuthServiceImpl) ValidateToken(ctx context.Context, tokenString string) (*Claims, error) {
+	if strings.HasPrefix(tokenString, patPrefix) {
+		return s.validateAccessToken(ctx, tokenString)
+	}
+	// An mfa pending token is signed with the same key, but it must not
+	// authenticate requests until VerifyMFA has exchanged it.
+	if _, err := s.parseMFAPendingToken(tokenString); err == nil {
+		return nil, errors.New("mfa verification required")
+	}
+	return s.parseClaims(tokenString)
+}
+
+// RefreshSession redeems refreshToken for a fresh access token, rotating
+// the session's refresh token in the process. Presenting a refresh token
+// that has already been rotated away is treated as reuse of a stolen
+// token: the whole rotation family is revoked and the caller must log in
+// again.
+func (s *AuthServiceImpl) RefreshSession(ctx context.Context, refreshToken string) (*LoginResponse, error) {
+	sessionID, secret, err := parseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	familyID, userID, err := s.userRepo.LookupSessionFamily(ctx, sessionID)
+	if err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	session, err := s.userRepo.GetSession(ctx, userID, sessionID)
+	if err != nil {
+		if revokeErr := s.userRepo.RevokeSessionFamily(ctx, userID, familyID); revokeErr != nil {
+			return nil, fmt.Errorf("failed to revoke compromised session family: %w", revokeErr)
+		}
+		return nil, errors.New("refresh token reuse detected, session family revoked")
+	}
+
+	if hashRefreshSecret(secret) != session.RefreshTokenHash {
+		if revokeErr := s.userRepo.RevokeSessionFamily(ctx, userID, familyID); revokeErr != nil {
+			return nil, fmt.Errorf("failed to revoke compromised session family: %w", revokeErr)
+		}
+		return nil, errors.New("invalid refresh token")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.RevokeSession(ctx, userID, sessionID); err != nil {
+		return nil, fmt.Errorf("failed to revoke rotated session: %w", err)
+	}
+
+	accessToken, err := s.generateToken(user)
 	if err != nil {
 		return nil, errors.New("failed to generate token")
 	}
 
-	// Create session
-	session := &UserSession{
-		UserID:    user.ID,
-		TokenHash: token, // In production, you'd hash this
-		ExpiresAt: time.Now().Add(s.config.TokenExpiration),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	newSessionID := uuid.New().String()
+	newRefreshToken, newRefreshHash, err := generateRefreshToken(newSessionID)
+	if err != nil {
+		return nil, errors.New("failed to generate refresh token")
 	}
 
-	if err := s.repo.CreateSession(ctx, session); err != nil {
+	now := time.Now()
+	newSession := &UserSession{
+		ID:               newSessionID,
+		UserID:           user.ID,
+		FamilyID:         familyID,
+		RefreshTokenHash: newRefreshHash,
+		UserAgent:        session.UserAgent,
+		IP:               session.IP,
+		ExpiresAt:        session.ExpiresAt,
+		LastSeenAt:       now,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	if _, err := s.userRepo.CreateSession(ctx, newSession); err != nil {
 		return nil, errors.New("failed to create session")
 	}
 
-	// Return response
 	return &LoginResponse{
-		Token:     token,
-		ExpiresAt: session.ExpiresAt,
-		User:      user.ToResponse(),
+		AccessToken:  accessToken,
+		SessionID:    newSessionID,
+		RefreshToken: newRefreshToken,
+		ExpiresAt:    now.Add(s.authConfig.TokenExpiration),
+		User:         user.ToResponse(),
 	}, nil
 }
 
-func (s *AuthServiceImpl) Logout(ctx context.Context, sessionID string) error {
-	//TODO implement me
-	panic("implement me")
+// generateRefreshToken mints an opaque refresh token for sessionID,
+// returning the raw token to hand to the client and the hash to persist.
+// The raw token embeds its session ID so a later refresh can look up the
+// session directly without a secondary index.
+func generateRefreshToken(sessionID string) (raw string, hash string, err error) {
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", err
+	}
+	secret := hex.EncodeToString(secretBytes)
+
+	return sessionID + "." + secret, hashRefreshSecret(secret), nil
 }
 
-// ValidateToken validates a JWT token and returns the user ID
-func (s *AuthServiceImpl) ValidateToken(tokenString string) (string, error) {
-	// Parse the token
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
+// parseRefreshToken splits an opaque refresh token back into its session
+// ID and secret.
+func parseRefreshToken(raw string) (sessionID, secret string, err error) {
+	parts := strings.SplitN(raw, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.New("malformed refresh token")
+	}
+	return parts[0], parts[1], nil
+}
 
-		return []byte(s.authConfig.JWTSecret), nil
-	})
+// hashRefreshSecret hashes a refresh token's secret half for storage. The
+// secret is already 256 bits of random data, so a fast hash (rather than
+// bcrypt) is sufficient.
+func hashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// OAuthAuthURL builds the authorization redirect URL for the named
+// provider, to send the client to the provider's consent screen.
+func (s *AuthServiceImpl) OAuthAuthURL(providerName, state string) (string, error) {
+	provider, ok := s.authConfig.OAuthProviders[providerName]
+	if !ok {
+		return "", fmt.Errorf("unknown oauth provider: %s", providerName)
+	}
+	return provider.AuthURL(state), nil
+}
 
+// LoginWithProvider exchanges an OAuth2/OIDC authorization code for the
+// user's profile claims, auto-provisioning or linking an account on first
+// login, and returns the same LoginResponse the local login path returns.
+func (s *AuthServiceImpl) LoginWithProvider(ctx context.Context, providerName, code, state string) (*LoginResponse, error) {
+	provider, ok := s.authConfig.OAuthProviders[providerName]
+	if !ok {
+		return nil, fmt.Errorf("unknown oauth provider: %s", providerName)
+	}
+
+	claims, err := provider.AttemptLogin(ctx, code, state)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("oauth login failed: %w", err)
 	}
 
-	// Extract claims
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		// Check token expiration
-		exp, ok := claims["exp"].(float64)
-		if !ok {
-			return "", errors.New("invalid token expiration")
-		}
+	email := claims.GetString("email")
+	if email == "" {
+		return nil, errors.New("oauth provider did not return an email claim")
+	}
+	subject := claims.GetStringFromKeysOrEmpty("sub", "id")
+	if subject == "" {
+		return nil, errors.New("oauth provider did not return a subject claim")
+	}
 
-		if time.Unix(int64(exp), 0).Before(time.Now()) {
-			return "", errors.New("token expired")
+	user, err := s.userRepo.GetByProviderSubject(ctx, providerName, subject)
+	if err != nil {
+		user, err = s.provisionOrLinkOAuthUser(ctx, providerName, subject, email, claims)
+		if err != nil {
+			return nil, err
 		}
+	}
+
+	return s.issueLoginResponse(ctx, user)
+}
 
-		// Get user ID
-		userID, ok := claims["sub"].(string)
-		if !ok {
-			return "", errors.New("invalid user ID in token")
+// provisionOrLinkOAuthUser creates a new account from the provider's
+// claims, or links the provider to an existing local account that shares
+// the same email.
+func (s *AuthServiceImpl) provisionOrLinkOAuthUser(ctx context.Context, providerName, subject, email string, claims providers.UserInfoFields) (*User, error) {
+	subjectCopy := subject
+
+	if existing, err := s.userRepo.GetByEmail(ctx, email); err == nil && existing != nil {
+		// Only link to an existing account if the provider itself vouches
+		// for the email; otherwise anyone claiming an arbitrary email at
+		// a generic OIDC IdP could take over the matching local account.
+		if !claims.GetBoolean("email_verified") {
+			return nil, errors.New("oauth email is not verified, cannot link to an existing account")
+		}
+		existing.AuthType = providerName
+		existing.ProviderSubject = &subjectCopy
+		if err := s.userRepo.Update(ctx, existing); err != nil {
+			return nil, fmt.Errorf("failed to link oauth account: %w", err)
 		}
+		return existing, nil
+	}
 
-		return userID, nil
+	user := &User{
+		Email:           email,
+		Username:        email,
+		FirstName:       claims.GetStringFromKeysOrEmpty("given_name", "name"),
+		LastName:        claims.GetString("family_name"),
+		ProfilePicture:  claims.GetStringFromKeysOrEmpty("picture", "avatar_url"),
+		AuthType:        providerName,
+		ProviderSubject: &subjectCopy,
+		IsVerified:      claims.GetBoolean("email_verified"),
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to provision oauth user: %w", err)
 	}
 
-	return "", errors.New("invalid token")
+	return user, nil
 }
 
-func (s *AuthServiceImpl) RefreshSession(ctx context.Context, sessionID string) (*LoginResponse, error) {
-	//TODO implement me
-	panic("implement me")
+// issueLoginResponse generates an access token, creates a new session with
+// a freshly rotated refresh token, and builds the LoginResponse shared by
+// the local and OAuth login paths.
+func (s *AuthServiceImpl) issueLoginResponse(ctx context.Context, user *User) (*LoginResponse, error) {
+	accessToken, err := s.generateToken(user)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	sessionID := uuid.New().String()
+	refreshToken, refreshHash, err := generateRefreshToken(sessionID)
+	if err != nil {
+		return nil, errors.New("failed to generate refresh token")
+	}
+
+	now := time.Now()
+	session := &UserSession{
+		ID:               sessionID,
+		UserID:           user.ID,
+		FamilyID:         sessionID,
+		RefreshTokenHash: refreshHash,
+		ExpiresAt:        now.Add(s.authConfig.RefreshTokenTTL),
+		LastSeenAt:       now,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	if _, err := s.userRepo.CreateSession(ctx, session); err != nil {
+		return nil, errors.New("failed to create session")
+	}
+
+	return &LoginResponse{
+		AccessToken:  accessToken,
+		SessionID:    sessionID,
+		RefreshToken: refreshToken,
+		ExpiresAt:    now.Add(s.authConfig.TokenExpiration),
+		User:         user.ToResponse(),
+	}, nil
+}
+
+// SendEmailOTP generates a code, stores its hash, and emails it to the
+// account for the given purpose.
+func (s *AuthServiceImpl) SendEmailOTP(ctx context.Context, email string, purpose OTPPurpose) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return errors.New("no account found for that email")
+	}
+
+	code, err := generateNumericOTP(otpCodeLength)
+	if err != nil {
+		return fmt.Errorf("failed to generate otp code: %w", err)
+	}
+
+	if err := s.authConfig.OTPStore.Store(ctx, purpose, user.ID, code, 0); err != nil {
+		return err
+	}
+
+	if err := s.authConfig.Mailer.Send(ctx, user.Email, mail.TemplateOTP, mail.OTPTemplateData{
+		Code:    code,
+		Purpose: string(purpose),
+		TTL:     "15 minutes",
+	}); err != nil {
+		return fmt.Errorf("failed to send otp email: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyEmailOTP checks code against the one most recently sent for
+// (email, purpose), clearing it on success so it can't be reused.
+func (s *AuthServiceImpl) VerifyEmailOTP(ctx context.Context, email string, purpose OTPPurpose, code string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return errors.New("no account found for that email")
+	}
+
+	ok, err := s.authConfig.OTPStore.Verify(ctx, purpose, user.ID, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("invalid otp code")
+	}
+
+	return s.authConfig.OTPStore.Clear(ctx, purpose, user.ID)
 }
 
+// RequestPasswordReset emails a password-reset OTP code to the account.
 func (s *AuthServiceImpl) RequestPasswordReset(ctx context.Context, req PasswordResetRequest) error {
-	//TODO implement me
-	panic("implement me")
+	return s.SendEmailOTP(ctx, req.Email, OTPPurposePasswordReset)
 }
 
-func (s *AuthServiceImpl) ResetPassword(ctx context.Context, token, newPassword string) error {
-	//TODO implement me
-	panic("implement me")
+// ResetPassword verifies the OTP code sent by RequestPasswordReset, sets
+// the new password, and rotates the user's active sessions.
+func (s *AuthServiceImpl) ResetPassword(ctx context.Context, email, code, newPassword string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return errors.New("no account found for that email")
+	}
+
+	ok, err := s.authConfig.OTPStore.Verify(ctx, OTPPurposePasswordReset, user.ID, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("invalid otp code")
+	}
+	if err := s.authConfig.OTPStore.Clear(ctx, OTPPurposePasswordReset, user.ID); err != nil {
+		return err
+	}
+
+	hashedPassword, err := HashPassword(newPassword)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	user.PasswordHash = hashedPassword
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.CleanSession(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to rotate sessions: %w", err)
+	}
+
+	return nil
 }
@@ -0,0 +1,61 @@
+package user
+
+import "testing"
+
+func TestGenerateAndParseRefreshToken(t *testing.T) {
+	raw, hash, err := generateRefreshToken("session-123")
+	if err != nil {
+		t.Fatalf("generateRefreshToken returned error: %v", err)
+	}
+	if hash == "" {
+		t.Fatal("expected a non-empty hash")
+	}
+
+	sessionID, secret, err := parseRefreshToken(raw)
+	if err != nil {
+		t.Fatalf("parseRefreshToken returned error: %v", err)
+	}
+	if sessionID != "session-123" {
+		t.Fatalf("expected sessionID %q, got %q", "session-123", sessionID)
+	}
+	if secret == "" {
+		t.Fatal("expected a non-empty secret")
+	}
+
+	if hashRefreshSecret(secret) != hash {
+		t.Fatal("hashRefreshSecret(secret) does not match the hash returned by generateRefreshToken")
+	}
+}
+
+func TestGenerateRefreshTokenIsUnpredictable(t *testing.T) {
+	rawA, hashA, err := generateRefreshToken("session-123")
+	if err != nil {
+		t.Fatalf("generateRefreshToken returned error: %v", err)
+	}
+	rawB, hashB, err := generateRefreshToken("session-123")
+	if err != nil {
+		t.Fatalf("generateRefreshToken returned error: %v", err)
+	}
+
+	if rawA == rawB {
+		t.Fatal("expected two refresh tokens for the same session to differ")
+	}
+	if hashA == hashB {
+		t.Fatal("expected two refresh token hashes for the same session to differ")
+	}
+}
+
+func TestParseRefreshTokenRejectsMalformedInput(t *testing.T) {
+	cases := []string{
+		"",
+		"no-separator",
+		".missing-session-id",
+		"missing-secret.",
+	}
+
+	for _, raw := range cases {
+		if _, _, err := parseRefreshToken(raw); err == nil {
+			t.Errorf("expected parseRefreshToken(%q) to fail", raw)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package user
+
+import (
+	"errors"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"had-service/internal/user/role"
+)
+
+// Claims is the access token payload: RFC 7519's registered claims plus
+// the authorization data middleware needs to enforce RBAC.
+type Claims struct {
+	Roles  []role.Role `json:"roles"`
+	Scopes []string    `json:"scopes"`
+	// IsPAT marks claims built from a personal access token rather than a
+	// session login. Scope restrictions (RequireScope) only apply to PAT
+	// requests; a human's session token carries their full role-based
+	// access regardless of Scopes.
+	IsPAT bool `json:"-"`
+	jwt.RegisteredClaims
+}
+
+// HasRole reports whether the token carries any of the given roles.
+func (c *Claims) HasRole(roles ...role.Role) bool {
+	for _, want := range roles {
+		for _, have := range c.Roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the token carries any of the given scopes.
+func (c *Claims) HasScope(scopes ...string) bool {
+	for _, want := range scopes {
+		for _, have := range c.Scopes {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseClaims validates tokenString's signature and expiry and returns its
+// Claims.
+func (s *AuthServiceImpl) parseClaims(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(s.authConfig.JWTSecret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+
+	"had-service/internal/user/role"
 )
 
 // User represents the user model
@@ -14,7 +16,26 @@ type User struct {
 	Username     string `json:"username" gorm:"uniqueIndex;not null"`
 	Email        string `json:"email" gorm:"uniqueIndex;not null"`
 	PasswordHash string `json:"-" gorm:"not null"`
-	LuckyNumber  string `json:"lucky_number" gorm:"not null"` // for password reset safety
+
+	// AuthType is "local" for email/password accounts, or the provider
+	// name ("google", "github", "oidc") for social login accounts.
+	AuthType string `json:"auth_type" gorm:"not null;default:local;uniqueIndex:idx_provider_subject"`
+	// ProviderSubject is the provider's stable user id ("sub" claim), nil
+	// for local accounts. A pointer so Postgres treats every local
+	// account's NULL as distinct instead of colliding on the unique index.
+	// Indexed together with AuthType (matching GetByProviderSubject's
+	// lookup scope) so two providers can't collide on a shared subject.
+	ProviderSubject *string `json:"-" gorm:"uniqueIndex:idx_provider_subject"`
+
+	// TOTP-based two-factor authentication
+	TOTPSecret    string   `json:"-" gorm:"column:totp_secret"`
+	TOTPEnabled   bool     `json:"totp_enabled" gorm:"column:totp_enabled;default:false"`
+	RecoveryCodes []string `json:"-" gorm:"column:recovery_codes"` // bcrypt-hashed, each usable once
+
+	// Role gates access to coarse-grained admin/moderation endpoints;
+	// Scopes grant finer-grained permissions on top of it.
+	Role   role.Role `json:"role" gorm:"column:role;not null;default:user"`
+	Scopes []string  `json:"scopes" gorm:"column:scopes"`
 
 	FirstName string `json:"first_name" gorm:"not null"`
 	LastName  string `json:"last_name" gorm:"not null"`
@@ -43,19 +64,71 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.ID == "" {
 		u.ID = uuid.New().String()
 	}
+	if u.Role == "" {
+		u.Role = role.User
+	}
 	return nil
 }
 
-// UserSession represents a login session (stored in Redis)
+// UserSession represents one logged-in device (stored in Redis, never in
+// Postgres). Each session belongs to a refresh-token rotation family:
+// refreshing rotates ID and RefreshTokenHash but keeps FamilyID, so reuse
+// of a retired refresh token can be recognized and the whole family
+// revoked.
 type UserSession struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	TokenHash string    `json:"-"`
-	UserAgent string    `json:"user_agent"`
-	IP        string    `json:"ip"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID               string    `json:"id"`
+	UserID           string    `json:"user_id"`
+	FamilyID         string    `json:"-"`
+	RefreshTokenHash string    `json:"-"`
+	UserAgent        string    `json:"user_agent"`
+	IP               string    `json:"ip"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	LastSeenAt       time.Time `json:"last_seen_at"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// AccessToken is a personal access token (PAT) for programmatic API
+// access, persisted in Postgres unlike UserSession. Only TokenHash is
+// ever stored; the plaintext is shown to the user once, at creation.
+type AccessToken struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:uuid"`
+	UserID     string     `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	Scopes     []string   `json:"scopes" gorm:"column:scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (t *AccessToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// CreateAccessTokenRequest requests a new personal access token.
+type CreateAccessTokenRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateAccessTokenResponse returns the plaintext token exactly once,
+// alongside the persisted metadata that ListAccessTokens will echo back.
+type CreateAccessTokenResponse struct {
+	Token       string      `json:"token"`
+	AccessToken AccessToken `json:"access_token"`
+}
+
+// CheckEmailAndUsernameRequest checks whether a username/email pair is
+// still available before the caller commits to a full registration.
+type CheckEmailAndUsernameRequest struct {
+	Username string `json:"username" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
 }
 
 // CreateUserRequest represents the request to create a new user
@@ -97,18 +170,32 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// PasswordResetRequest represents a password reset request
+// PasswordResetRequest requests an email-OTP code to start a password reset.
 type PasswordResetRequest struct {
-	Email       string `json:"email" binding:"required,email"`
-	LuckyNumber int    `json:"lucky_number" binding:"required"`
+	Email string `json:"email" binding:"required,email"`
 }
 
-// PasswordResetConfirmRequest represents a password reset confirmation
+// PasswordResetConfirmRequest completes a password reset with the code
+// sent to the user's email.
 type PasswordResetConfirmRequest struct {
-	Token    string `json:"token" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Code     string `json:"code" binding:"required"`
 	Password string `json:"password" binding:"required,min=8"`
 }
 
+// SendOTPRequest requests an email-OTP code for a given purpose.
+type SendOTPRequest struct {
+	Email   string     `json:"email" binding:"required,email"`
+	Purpose OTPPurpose `json:"purpose" binding:"required,oneof=password_reset email_verify reauthenticate"`
+}
+
+// VerifyOTPRequest verifies a previously-sent email-OTP code.
+type VerifyOTPRequest struct {
+	Email   string     `json:"email" binding:"required,email"`
+	Purpose OTPPurpose `json:"purpose" binding:"required,oneof=password_reset email_verify reauthenticate"`
+	Code    string     `json:"code" binding:"required"`
+}
+
 // UserProfile represents the user profile data
 type UserProfile struct {
 	ID        string `json:"id"`
@@ -151,13 +238,29 @@ type UserResponse struct {
 	IsPrivate  bool       `json:"is_private"`
 	IsVerified bool       `json:"is_verified"`
 	CreatedAt  time.Time  `json:"created_at"`
+
+	Role   role.Role `json:"role"`
+	Scopes []string  `json:"scopes"`
 }
 
-// LoginResponse contains the token and user info
+// LoginResponse contains the issued tokens and user info. AccessToken is a
+// short-lived JWT; RefreshToken is an opaque, one-time-rotatable token
+// that must be exchanged via AuthService.RefreshSession once the access
+// token expires.
+// When the account has 2FA enabled, Login returns MFARequired with a
+// PendingToken instead, and the caller must follow up with VerifyMFA.
 type LoginResponse struct {
-	Token     string       `json:"token"`
-	ExpiresAt time.Time    `json:"expires_at"`
-	User      UserResponse `json:"user"`
+	AccessToken string `json:"access_token,omitempty"`
+	// SessionID identifies the session this login/refresh created, for use
+	// with RevokeSession/Logout's X-Session-ID header. The refresh token
+	// itself is opaque, so this is the only supported way to learn it.
+	SessionID    string       `json:"session_id,omitempty"`
+	RefreshToken string       `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time    `json:"expires_at,omitempty"`
+	User         UserResponse `json:"user,omitempty"`
+
+	MFARequired  bool   `json:"mfa_required,omitempty"`
+	PendingToken string `json:"pending_token,omitempty"`
 }
 
 // HashPassword creates a hashed password from a plaintext password
@@ -190,6 +293,8 @@ func (u *User) ToResponse() UserResponse {
 		IsPrivate:      u.IsPrivate,
 		IsVerified:     u.IsVerified,
 		CreatedAt:      u.CreatedAt,
+		Role:           u.Role,
+		Scopes:         u.Scopes,
 	}
 }
 
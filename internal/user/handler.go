@@ -0,0 +1,457 @@
+package user
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"had-service/internal/user/role"
+)
+
+// Gin context keys RequireAuth stores the validated identity under.
+const (
+	contextUserIDKey = "userID"
+	contextClaimsKey = "claims"
+)
+
+// AuthHandler exposes AuthService over HTTP.
+type AuthHandler struct {
+	authService AuthService
+}
+
+func NewAuthHandler(authService AuthService) *AuthHandler {
+	return &AuthHandler{authService: authService}
+}
+
+// RegisterRoutes wires the auth endpoints onto the given router group.
+func (h *AuthHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.POST("/login", h.Login)
+	rg.POST("/refresh", h.RefreshSession)
+	rg.POST("/mfa/verify", h.VerifyMFA)
+
+	rg.POST("/otp/send", h.SendEmailOTP)
+	rg.POST("/otp/verify", h.VerifyEmailOTP)
+	rg.POST("/password/reset", h.RequestPasswordReset)
+	rg.POST("/password/reset/confirm", h.ConfirmPasswordReset)
+
+	oauth := rg.Group("/oauth/:provider")
+	oauth.GET("/start", h.OAuthStart)
+	oauth.GET("/callback", h.OAuthCallback)
+
+	mfa := rg.Group("/mfa", h.RequireAuth())
+	mfa.POST("/totp/enroll", h.EnrollTOTP)
+	mfa.POST("/totp/confirm", h.ConfirmTOTP)
+	mfa.POST("/totp/disable", h.DisableTOTP)
+
+	sessions := rg.Group("/sessions", h.RequireAuth())
+	sessions.GET("", h.ListSessions)
+	sessions.DELETE("/:sessionID", h.RevokeSession)
+	sessions.POST("/logout", h.Logout)
+
+	admin := rg.Group("/admin/users/:id", h.RequireAuth(), h.RequireRole(role.Admin))
+	admin.PUT("/roles", h.UpdateUserRole)
+	admin.PUT("/scopes", h.UpdateUserScopes)
+
+	tokens := rg.Group("/user/tokens", h.RequireAuth(), h.RequireScope(ScopeTokensManage))
+	tokens.POST("", h.CreateAccessToken)
+	tokens.GET("", h.ListAccessTokens)
+	tokens.DELETE("/:tokenID", h.RevokeAccessToken)
+}
+
+// RequireAuth validates the bearer token on the request and stores the
+// resulting user ID and claims in the gin context for downstream handlers.
+func (h *AuthHandler) RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing authorization token"})
+			return
+		}
+
+		claims, err := h.authService.ValidateToken(c.Request.Context(), token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(contextUserIDKey, claims.Subject)
+		c.Set(contextClaimsKey, claims)
+		c.Next()
+	}
+}
+
+// RequireRole aborts the request with 403 unless RequireAuth's claims
+// carry at least one of roles. Must run after RequireAuth.
+func (h *AuthHandler) RequireRole(roles ...role.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := c.MustGet(contextClaimsKey).(*Claims)
+		if !ok || !claims.HasRole(roles...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient role"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScope aborts a PAT-authenticated request with 403 unless
+// RequireAuth's claims carry at least one of scopes. Session logins carry
+// a user's full role-based access regardless of Scopes, so this only
+// restricts personal access tokens. Must run after RequireAuth.
+func (h *AuthHandler) RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := c.MustGet(contextClaimsKey).(*Claims)
+		if !ok || (claims.IsPAT && !claims.HasScope(scopes...)) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient scope"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authService.Login(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Logout revokes the caller's current session. Since the access token
+// alone doesn't identify which session issued it, the client must also
+// send the session ID it was given at login.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	userID := c.GetString(contextUserIDKey)
+	sessionID := c.GetHeader("X-Session-ID")
+
+	if err := h.authService.Logout(c.Request.Context(), userID, sessionID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type refreshSessionRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshSession exchanges a refresh token for a new access token,
+// rotating the session's refresh token in the process.
+func (h *AuthHandler) RefreshSession(c *gin.Context) {
+	var req refreshSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authService.RefreshSession(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListSessions lists the authenticated user's logged-in devices.
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID := c.GetString(contextUserIDKey)
+
+	sessions, err := h.authService.ListSessions(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession logs out a specific device of the authenticated user.
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID := c.GetString(contextUserIDKey)
+	sessionID := c.Param("sessionID")
+
+	if err := h.authService.RevokeSession(c.Request.Context(), userID, sessionID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// OAuthStart redirects the client to the provider's consent screen.
+func (h *AuthHandler) OAuthStart(c *gin.Context) {
+	providerName := c.Param("provider")
+
+	state := uuid.New().String()
+	c.SetCookie("oauth_state", state, 600, "/", "", false, true)
+
+	authURL, err := h.authService.OAuthAuthURL(providerName, state)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OAuthCallback exchanges the authorization code for a session and
+// returns the same LoginResponse the local login path returns.
+func (h *AuthHandler) OAuthCallback(c *gin.Context) {
+	providerName := c.Param("provider")
+	code := c.Query("code")
+	state := c.Query("state")
+
+	expectedState, _ := c.Cookie("oauth_state")
+	if expectedState == "" || expectedState != state {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid oauth state"})
+		return
+	}
+
+	resp, err := h.authService.LoginWithProvider(c.Request.Context(), providerName, code, state)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// EnrollTOTP starts 2FA enrollment for the authenticated user.
+func (h *AuthHandler) EnrollTOTP(c *gin.Context) {
+	userID := c.GetString(contextUserIDKey)
+
+	secret, otpauthURL, err := h.authService.EnrollTOTP(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"secret": secret, "otpauth_url": otpauthURL})
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required,len=6"`
+}
+
+// ConfirmTOTP finishes 2FA enrollment and returns the one-time recovery codes.
+func (h *AuthHandler) ConfirmTOTP(c *gin.Context) {
+	userID := c.GetString(contextUserIDKey)
+
+	var req confirmTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recoveryCodes, err := h.authService.ConfirmTOTP(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+}
+
+type disableTOTPRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+func (h *AuthHandler) DisableTOTP(c *gin.Context) {
+	userID := c.GetString(contextUserIDKey)
+
+	var req disableTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.DisableTOTP(c.Request.Context(), userID, req.Code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type verifyMFARequest struct {
+	PendingToken string `json:"pending_token" binding:"required"`
+	Code         string `json:"code" binding:"required"`
+}
+
+// VerifyMFA completes a login that Login short-circuited with mfa_required.
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req verifyMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authService.VerifyMFA(c.Request.Context(), req.PendingToken, req.Code)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// SendEmailOTP issues a fresh email-OTP code for the given purpose.
+func (h *AuthHandler) SendEmailOTP(c *gin.Context) {
+	var req SendOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.SendEmailOTP(c.Request.Context(), req.Email, req.Purpose); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// VerifyEmailOTP checks a previously-sent email-OTP code.
+func (h *AuthHandler) VerifyEmailOTP(c *gin.Context) {
+	var req VerifyOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.VerifyEmailOTP(c.Request.Context(), req.Email, req.Purpose, req.Code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RequestPasswordReset emails a password-reset OTP code to the account.
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.RequestPasswordReset(c.Request.Context(), req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type updateUserRoleRequest struct {
+	Role role.Role `json:"role" binding:"required"`
+}
+
+// UpdateUserRole sets a user's RBAC role. Admin-only.
+func (h *AuthHandler) UpdateUserRole(c *gin.Context) {
+	var req updateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.UpdateUserRole(c.Request.Context(), c.Param("id"), req.Role); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type updateUserScopesRequest struct {
+	Scopes []string `json:"scopes" binding:"required"`
+}
+
+// UpdateUserScopes replaces a user's scope grants. Admin-only.
+func (h *AuthHandler) UpdateUserScopes(c *gin.Context) {
+	var req updateUserScopesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.UpdateUserScopes(c.Request.Context(), c.Param("id"), req.Scopes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CreateAccessToken mints a new personal access token for the
+// authenticated user. The plaintext token is returned exactly once.
+func (h *AuthHandler) CreateAccessToken(c *gin.Context) {
+	userID := c.GetString(contextUserIDKey)
+
+	var req CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authService.CreateAccessToken(c.Request.Context(), userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// ListAccessTokens lists the authenticated user's personal access tokens.
+func (h *AuthHandler) ListAccessTokens(c *gin.Context) {
+	userID := c.GetString(contextUserIDKey)
+
+	tokens, err := h.authService.ListAccessTokens(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// RevokeAccessToken deletes one of the authenticated user's personal
+// access tokens.
+func (h *AuthHandler) RevokeAccessToken(c *gin.Context) {
+	userID := c.GetString(contextUserIDKey)
+	tokenID := c.Param("tokenID")
+
+	if err := h.authService.RevokeAccessToken(c.Request.Context(), userID, tokenID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ConfirmPasswordReset verifies the OTP code and sets the new password.
+func (h *AuthHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req PasswordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ResetPassword(c.Request.Context(), req.Email, req.Code, req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
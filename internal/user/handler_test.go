@@ -0,0 +1,99 @@
+package user
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"had-service/internal/user/role"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestContext builds a gin.Context with claims already set, as if
+// RequireAuth had already run.
+func newTestContext(claims *Claims) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Set(contextClaimsKey, claims)
+	return c, w
+}
+
+func runMiddleware(mw gin.HandlerFunc, c *gin.Context) {
+	mw(c)
+}
+
+func TestRequireRoleAllowsMatchingRole(t *testing.T) {
+	h := &AuthHandler{}
+	c, w := newTestContext(&Claims{Roles: []role.Role{role.Admin}})
+
+	runMiddleware(h.RequireRole(role.Admin), c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected no abort (status left at default), got %d", w.Code)
+	}
+	if c.IsAborted() {
+		t.Fatal("expected RequireRole to let a matching role through")
+	}
+}
+
+func TestRequireRoleRejectsWrongRole(t *testing.T) {
+	h := &AuthHandler{}
+	c, w := newTestContext(&Claims{Roles: []role.Role{role.User}})
+
+	runMiddleware(h.RequireRole(role.Admin), c)
+
+	if !c.IsAborted() {
+		t.Fatal("expected RequireRole to abort for a non-matching role")
+	}
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", w.Code)
+	}
+}
+
+func TestRequireScopeAllowsSessionTokenRegardlessOfScopes(t *testing.T) {
+	h := &AuthHandler{}
+	c, w := newTestContext(&Claims{IsPAT: false, Scopes: nil})
+
+	runMiddleware(h.RequireScope(ScopeTokensManage), c)
+
+	if c.IsAborted() {
+		t.Fatal("expected a session (non-PAT) token to bypass scope checks")
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected no abort, got status %d", w.Code)
+	}
+}
+
+func TestRequireScopeRejectsPATMissingScope(t *testing.T) {
+	h := &AuthHandler{}
+	c, w := newTestContext(&Claims{IsPAT: true, Scopes: []string{"read:profile"}})
+
+	runMiddleware(h.RequireScope(ScopeTokensManage), c)
+
+	if !c.IsAborted() {
+		t.Fatal("expected a PAT lacking the required scope to be rejected")
+	}
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", w.Code)
+	}
+}
+
+func TestRequireScopeAllowsPATWithScope(t *testing.T) {
+	h := &AuthHandler{}
+	c, w := newTestContext(&Claims{IsPAT: true, Scopes: []string{ScopeTokensManage}})
+
+	runMiddleware(h.RequireScope(ScopeTokensManage), c)
+
+	if c.IsAborted() {
+		t.Fatal("expected a PAT carrying the required scope to pass")
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected no abort, got status %d", w.Code)
+	}
+}
@@ -0,0 +1,299 @@
+package user
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	totpPeriod     = 30 * time.Second
+	totpDigits     = 6
+	totpStepWindow = 1 // accept codes from the previous/next 30s step too
+
+	// recoveryCodeCount is how many one-time recovery codes are minted
+	// when a user confirms enrollment.
+	recoveryCodeCount = 10
+
+	// maxMFAFailures is how many failed codes a user may submit before
+	// further verification attempts are rejected.
+	maxMFAFailures = 5
+)
+
+// EnrollTOTP starts 2FA enrollment: it generates a new TOTP secret, stores
+// it unconfirmed on the user, and returns the secret plus its otpauth://
+// URL for QR provisioning. 2FA isn't enforced until ConfirmTOTP succeeds.
+func (s *AuthServiceImpl) EnrollTOTP(ctx context.Context, userID string) (string, string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+
+	user.TOTPSecret = secret
+	user.TOTPEnabled = false
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return "", "", fmt.Errorf("failed to store totp secret: %w", err)
+	}
+
+	return secret, totpAuthURL("had-service", user.Email, secret), nil
+}
+
+// ConfirmTOTP verifies the enrollment code against the pending secret,
+// enables 2FA, and returns a freshly minted set of recovery codes. The
+// plaintext codes are returned exactly once; only their bcrypt hashes are
+// persisted.
+func (s *AuthServiceImpl) ConfirmTOTP(ctx context.Context, userID string, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("totp enrollment has not been started")
+	}
+
+	if err := s.checkMFARateLimit(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if !validateTOTP(user.TOTPSecret, code, time.Now()) {
+		if _, incErr := s.userRepo.IncrementMFAFailure(ctx, userID); incErr != nil {
+			return nil, incErr
+		}
+		return nil, errors.New("invalid totp code")
+	}
+	if err := s.userRepo.ResetMFAFailure(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	plainCodes, hashedCodes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+
+	user.TOTPEnabled = true
+	user.RecoveryCodes = hashedCodes
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to enable totp: %w", err)
+	}
+
+	return plainCodes, nil
+}
+
+// DisableTOTP turns off 2FA after verifying a current TOTP or recovery code.
+func (s *AuthServiceImpl) DisableTOTP(ctx context.Context, userID string, code string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return errors.New("totp is not enabled")
+	}
+
+	if err := s.checkMFARateLimit(ctx, userID); err != nil {
+		return err
+	}
+
+	if !s.verifyTOTPOrRecoveryCode(user, code) {
+		if _, incErr := s.userRepo.IncrementMFAFailure(ctx, userID); incErr != nil {
+			return incErr
+		}
+		return errors.New("invalid totp or recovery code")
+	}
+	if err := s.userRepo.ResetMFAFailure(ctx, userID); err != nil {
+		return err
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.RecoveryCodes = nil
+	return s.userRepo.Update(ctx, user)
+}
+
+// VerifyMFA completes the login flow started by Login's mfa_pending
+// response: it validates the pending token and TOTP/recovery code, then
+// issues the same LoginResponse a password-only login would.
+func (s *AuthServiceImpl) VerifyMFA(ctx context.Context, pendingToken string, code string) (*LoginResponse, error) {
+	userID, err := s.parseMFAPendingToken(pendingToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired pending token: %w", err)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, errors.New("totp is not enabled for this user")
+	}
+
+	if err := s.checkMFARateLimit(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if !s.verifyTOTPOrRecoveryCode(user, code) {
+		if _, incErr := s.userRepo.IncrementMFAFailure(ctx, userID); incErr != nil {
+			return nil, incErr
+		}
+		return nil, errors.New("invalid totp or recovery code")
+	}
+	if err := s.userRepo.ResetMFAFailure(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	// verifyTOTPOrRecoveryCode drops a consumed recovery code from
+	// user.RecoveryCodes in memory; persist that so the code can't be
+	// reused.
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to persist recovery code use: %w", err)
+	}
+
+	return s.issueLoginResponse(ctx, user)
+}
+
+// checkMFARateLimit rejects verification attempts once a user has racked
+// up maxMFAFailures failed codes, until the repository's failure counter
+// expires. Rejected attempts are not counted, so the counter stops at the
+// threshold and the lockout stays at the repository's base window; it
+// does not grow with further attempts.
+func (s *AuthServiceImpl) checkMFARateLimit(ctx context.Context, userID string) error {
+	failures, err := s.userRepo.GetMFAFailureCount(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if failures >= maxMFAFailures {
+		return errors.New("too many failed verification attempts, try again later")
+	}
+	return nil
+}
+
+// verifyTOTPOrRecoveryCode checks code against the user's live TOTP secret
+// first, then against their unused recovery codes, consuming the recovery
+// code on a match.
+func (s *AuthServiceImpl) verifyTOTPOrRecoveryCode(user *User, code string) bool {
+	if validateTOTP(user.TOTPSecret, code, time.Now()) {
+		return true
+	}
+
+	for i, hashed := range user.RecoveryCodes {
+		if CheckPasswordHash(code, hashed) {
+			user.RecoveryCodes = append(user.RecoveryCodes[:i], user.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// generateTOTPSecret creates a random base32-encoded secret suitable for
+// RFC 6238 TOTP generation.
+func generateTOTPSecret() (string, error) {
+	raw := make([]byte, 20) // 160 bits, matches SHA-1's output size
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw), nil
+}
+
+// totpAuthURL builds the otpauth://totp/{issuer}:{email}?secret=...&issuer=...
+// URL used to provision authenticator apps via QR code.
+func totpAuthURL(issuer, email, secret string) string {
+	label := url.PathEscape(fmt.Sprintf("%s:%s", issuer, email))
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprintf("%d", totpDigits))
+	v.Set("period", fmt.Sprintf("%d", int(totpPeriod.Seconds())))
+
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, v.Encode())
+}
+
+// validateTOTP checks code against the RFC 6238 TOTP derived from secret
+// at t, accepting codes from totpStepWindow periods before/after to
+// tolerate clock drift.
+func validateTOTP(secret, code string, t time.Time) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+
+	counter := t.Unix() / int64(totpPeriod.Seconds())
+	for offset := -totpStepWindow; offset <= totpStepWindow; offset++ {
+		expected, err := totpCode(secret, counter+int64(offset))
+		if err != nil {
+			return false
+		}
+		if expected == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// totpCode computes the RFC 6238 HOTP value for secret at the given
+// 30-second counter step, using HMAC-SHA1 per the standard.
+func totpCode(secret string, counter int64) (string, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid totp secret encoding: %w", err)
+	}
+
+	var counterBytes [8]byte
+	binary.BigEndian.PutUint64(counterBytes[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counterBytes[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	truncated := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	code := truncated % uint32(math.Pow10(totpDigits))
+	return fmt.Sprintf("%0*d", totpDigits, code), nil
+}
+
+// generateRecoveryCodes mints n single-use recovery codes, returning both
+// the plaintext (shown once) and their bcrypt hashes (persisted).
+func generateRecoveryCodes(n int) (plain []string, hashed []string, err error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789" // no ambiguous chars
+
+	plain = make([]string, n)
+	hashed = make([]string, n)
+
+	for i := 0; i < n; i++ {
+		buf := make([]byte, 10)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+
+		var sb strings.Builder
+		for _, b := range buf {
+			sb.WriteByte(alphabet[int(b)%len(alphabet)])
+		}
+		code := sb.String()
+
+		hash, err := HashPassword(code)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		plain[i] = code
+		hashed[i] = hash
+	}
+
+	return plain, hashed, nil
+}
@@ -0,0 +1,116 @@
+package user
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTotpCodeIsDeterministic(t *testing.T) {
+	secret := "JBSWY3DPEHPK3PXP"
+
+	code, err := totpCode(secret, 12345)
+	if err != nil {
+		t.Fatalf("totpCode returned error: %v", err)
+	}
+	if len(code) != totpDigits {
+		t.Fatalf("expected %d digit code, got %q", totpDigits, code)
+	}
+
+	again, err := totpCode(secret, 12345)
+	if err != nil {
+		t.Fatalf("totpCode returned error: %v", err)
+	}
+	if code != again {
+		t.Fatalf("totpCode is not deterministic for a fixed counter: %q != %q", code, again)
+	}
+
+	other, err := totpCode(secret, 12346)
+	if err != nil {
+		t.Fatalf("totpCode returned error: %v", err)
+	}
+	if other == code {
+		t.Fatalf("totpCode produced the same code for different counters")
+	}
+}
+
+func TestTotpCodeRejectsInvalidSecret(t *testing.T) {
+	if _, err := totpCode("not-valid-base32!!", 1); err == nil {
+		t.Fatal("expected an error for a malformed base32 secret")
+	}
+}
+
+func TestValidateTOTPAcceptsCurrentCode(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatalf("generateTOTPSecret returned error: %v", err)
+	}
+
+	now := time.Now()
+	counter := now.Unix() / int64(totpPeriod.Seconds())
+	code, err := totpCode(secret, counter)
+	if err != nil {
+		t.Fatalf("totpCode returned error: %v", err)
+	}
+
+	if !validateTOTP(secret, code, now) {
+		t.Fatal("expected the current-step code to validate")
+	}
+}
+
+func TestValidateTOTPAcceptsAdjacentStepWithinWindow(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatalf("generateTOTPSecret returned error: %v", err)
+	}
+
+	now := time.Now()
+	counter := now.Unix()/int64(totpPeriod.Seconds()) - 1
+	code, err := totpCode(secret, counter)
+	if err != nil {
+		t.Fatalf("totpCode returned error: %v", err)
+	}
+
+	if !validateTOTP(secret, code, now) {
+		t.Fatal("expected the previous-step code to validate within totpStepWindow")
+	}
+}
+
+func TestValidateTOTPRejectsWrongCode(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatalf("generateTOTPSecret returned error: %v", err)
+	}
+
+	if validateTOTP(secret, "000000", time.Now()) {
+		t.Fatal("expected an arbitrary code to be rejected")
+	}
+}
+
+func TestValidateTOTPRejectsWrongLength(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatalf("generateTOTPSecret returned error: %v", err)
+	}
+
+	if validateTOTP(secret, "1234567", time.Now()) {
+		t.Fatal("expected a code of the wrong length to be rejected outright")
+	}
+}
+
+func TestValidateTOTPRejectsStaleCode(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatalf("generateTOTPSecret returned error: %v", err)
+	}
+
+	now := time.Now()
+	staleCounter := now.Unix()/int64(totpPeriod.Seconds()) - (totpStepWindow + 2)
+	code, err := totpCode(secret, staleCounter)
+	if err != nil {
+		t.Fatalf("totpCode returned error: %v", err)
+	}
+
+	if validateTOTP(secret, code, now) {
+		t.Fatal("expected a code outside totpStepWindow to be rejected")
+	}
+}
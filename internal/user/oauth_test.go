@@ -0,0 +1,106 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"had-service/internal/user/providers"
+)
+
+func newTestAuthService(repo *fakeUserRepository) *AuthServiceImpl {
+	return &AuthServiceImpl{
+		userRepo: repo,
+		authConfig: AuthServiceConfig{
+			JWTSecret: "test-secret",
+			Issuer:    "had-service-test",
+			Audience:  "had-service-test",
+		},
+	}
+}
+
+// TestProvisionOrLinkOAuthUserRejectsUnverifiedEmail guards against an
+// attacker registering an arbitrary, unverified email at a generic OIDC
+// IdP and using it to take over an existing local account that happens
+// to share that email.
+func TestProvisionOrLinkOAuthUserRejectsUnverifiedEmail(t *testing.T) {
+	repo := newFakeUserRepository()
+	victim := &User{
+		ID:       "victim-id",
+		Username: "victim",
+		Email:    "victim@example.com",
+		AuthType: "local",
+	}
+	if err := repo.Create(context.Background(), victim); err != nil {
+		t.Fatalf("failed to seed victim user: %v", err)
+	}
+
+	s := newTestAuthService(repo)
+	claims := providers.UserInfoFields{
+		"email":          "victim@example.com",
+		"email_verified": false,
+	}
+
+	_, err := s.provisionOrLinkOAuthUser(context.Background(), "oidc", "attacker-subject", "victim@example.com", claims)
+	if err == nil {
+		t.Fatal("expected linking to fail for an unverified email claim")
+	}
+
+	reloaded, getErr := repo.GetByID(context.Background(), "victim-id")
+	if getErr != nil {
+		t.Fatalf("failed to reload victim user: %v", getErr)
+	}
+	if reloaded.AuthType != "local" || reloaded.ProviderSubject != nil {
+		t.Fatal("victim's account was linked to the attacker's identity despite an unverified email claim")
+	}
+}
+
+// TestProvisionOrLinkOAuthUserLinksVerifiedEmail confirms a legitimate,
+// provider-verified email still links to the matching local account.
+func TestProvisionOrLinkOAuthUserLinksVerifiedEmail(t *testing.T) {
+	repo := newFakeUserRepository()
+	existing := &User{
+		ID:       "user-id",
+		Username: "someone",
+		Email:    "someone@example.com",
+		AuthType: "local",
+	}
+	if err := repo.Create(context.Background(), existing); err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	s := newTestAuthService(repo)
+	claims := providers.UserInfoFields{
+		"email":          "someone@example.com",
+		"email_verified": true,
+	}
+
+	linked, err := s.provisionOrLinkOAuthUser(context.Background(), "google", "google-subject", "someone@example.com", claims)
+	if err != nil {
+		t.Fatalf("expected linking to succeed for a verified email, got error: %v", err)
+	}
+
+	if linked.AuthType != "google" || linked.ProviderSubject == nil || *linked.ProviderSubject != "google-subject" {
+		t.Fatal("expected the existing account to be linked to the oauth identity")
+	}
+}
+
+// TestProvisionOrLinkOAuthUserProvisionsNewAccount confirms the
+// email-verified requirement only gates linking to an existing account,
+// not first-time provisioning.
+func TestProvisionOrLinkOAuthUserProvisionsNewAccount(t *testing.T) {
+	repo := newFakeUserRepository()
+	s := newTestAuthService(repo)
+
+	claims := providers.UserInfoFields{
+		"email":          "new-user@example.com",
+		"email_verified": false,
+	}
+
+	user, err := s.provisionOrLinkOAuthUser(context.Background(), "oidc", "new-subject", "new-user@example.com", claims)
+	if err != nil {
+		t.Fatalf("expected a brand new account to provision without error, got: %v", err)
+	}
+	if user.Email != "new-user@example.com" {
+		t.Fatalf("expected provisioned user to have email new-user@example.com, got %q", user.Email)
+	}
+}
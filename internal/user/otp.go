@@ -0,0 +1,135 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"had-service/constants"
+	"math/big"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OTPPurpose scopes an OTP code to the flow it was issued for, so a code
+// requested for one purpose can't be replayed against another.
+type OTPPurpose string
+
+const (
+	OTPPurposePasswordReset  OTPPurpose = "password_reset"
+	OTPPurposeEmailVerify    OTPPurpose = "email_verify"
+	OTPPurposeReauthenticate OTPPurpose = "reauthenticate"
+)
+
+const (
+	otpCodeLength  = 6
+	otpDefaultTTL  = 15 * time.Minute
+	otpMaxAttempts = 5
+)
+
+// OTPStore persists hashed, short-lived one-time codes keyed by purpose
+// and user, backing the email-OTP flows (password reset, email
+// verification, reauthentication).
+type OTPStore interface {
+	// Store hashes and saves code for (purpose, userID), expiring after ttl
+	// (or the store's default TTL if ttl <= 0), and resets the attempt
+	// counter.
+	Store(ctx context.Context, purpose OTPPurpose, userID, code string, ttl time.Duration) error
+
+	// Verify checks code against the stored hash, counting the attempt
+	// against otpMaxAttempts regardless of outcome.
+	Verify(ctx context.Context, purpose OTPPurpose, userID, code string) (bool, error)
+
+	// Clear deletes the stored code and attempt counter, e.g. after a
+	// successful verification.
+	Clear(ctx context.Context, purpose OTPPurpose, userID string) error
+}
+
+// RedisOTPStore is the production OTPStore, backed by Redis.
+type RedisOTPStore struct {
+	redis      redis.Client
+	defaultTTL time.Duration
+}
+
+// NewRedisOTPStore builds a RedisOTPStore. A non-positive defaultTTL
+// falls back to otpDefaultTTL (15 minutes).
+func NewRedisOTPStore(client redis.Client, defaultTTL time.Duration) *RedisOTPStore {
+	if defaultTTL <= 0 {
+		defaultTTL = otpDefaultTTL
+	}
+	return &RedisOTPStore{redis: client, defaultTTL: defaultTTL}
+}
+
+func (s *RedisOTPStore) Store(ctx context.Context, purpose OTPPurpose, userID, code string, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = s.defaultTTL
+	}
+
+	hash, err := HashPassword(code)
+	if err != nil {
+		return fmt.Errorf("failed to hash otp code: %w", err)
+	}
+
+	codeKey := fmt.Sprintf(constants.OTPCodeRK, purpose, userID)
+	attemptsKey := fmt.Sprintf(constants.OTPAttemptsRK, purpose, userID)
+
+	if err := s.redis.Set(ctx, codeKey, hash, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store otp code: %w", err)
+	}
+	if err := s.redis.Del(ctx, attemptsKey).Err(); err != nil {
+		return fmt.Errorf("failed to reset otp attempt counter: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisOTPStore) Verify(ctx context.Context, purpose OTPPurpose, userID, code string) (bool, error) {
+	codeKey := fmt.Sprintf(constants.OTPCodeRK, purpose, userID)
+	attemptsKey := fmt.Sprintf(constants.OTPAttemptsRK, purpose, userID)
+
+	attempts, err := s.redis.Incr(ctx, attemptsKey).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to track otp attempt: %w", err)
+	}
+	if attempts == 1 {
+		if err := s.redis.Expire(ctx, attemptsKey, s.defaultTTL).Err(); err != nil {
+			return false, fmt.Errorf("failed to set otp attempt expiration: %w", err)
+		}
+	}
+	if attempts > otpMaxAttempts {
+		return false, errors.New("too many otp attempts, request a new code")
+	}
+
+	hash, err := s.redis.Get(ctx, codeKey).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, errors.New("otp code expired or not found")
+		}
+		return false, fmt.Errorf("failed to read otp code: %w", err)
+	}
+
+	return CheckPasswordHash(code, hash), nil
+}
+
+func (s *RedisOTPStore) Clear(ctx context.Context, purpose OTPPurpose, userID string) error {
+	codeKey := fmt.Sprintf(constants.OTPCodeRK, purpose, userID)
+	attemptsKey := fmt.Sprintf(constants.OTPAttemptsRK, purpose, userID)
+	return s.redis.Del(ctx, codeKey, attemptsKey).Err()
+}
+
+// generateNumericOTP returns a random n-digit numeric code, e.g. "042817".
+func generateNumericOTP(n int) (string, error) {
+	const digits = "0123456789"
+
+	code := make([]byte, n)
+	for i := range code {
+		idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(digits))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = digits[idx.Int64()]
+	}
+
+	return string(code), nil
+}
@@ -0,0 +1,99 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestOTPStore(t *testing.T) *RedisOTPStore {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewRedisOTPStore(*client, time.Minute)
+}
+
+func TestRedisOTPStoreVerifyAcceptsCorrectCode(t *testing.T) {
+	store := newTestOTPStore(t)
+	ctx := context.Background()
+
+	if err := store.Store(ctx, OTPPurposePasswordReset, "user-1", "123456", 0); err != nil {
+		t.Fatalf("Store returned error: %v", err)
+	}
+
+	ok, err := store.Verify(ctx, OTPPurposePasswordReset, "user-1", "123456")
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected the stored code to verify")
+	}
+}
+
+func TestRedisOTPStoreVerifyRejectsWrongCode(t *testing.T) {
+	store := newTestOTPStore(t)
+	ctx := context.Background()
+
+	if err := store.Store(ctx, OTPPurposePasswordReset, "user-1", "123456", 0); err != nil {
+		t.Fatalf("Store returned error: %v", err)
+	}
+
+	ok, err := store.Verify(ctx, OTPPurposePasswordReset, "user-1", "000000")
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if ok {
+		t.Fatal("expected a wrong code to fail verification")
+	}
+}
+
+func TestRedisOTPStoreVerifyRejectsMissingCode(t *testing.T) {
+	store := newTestOTPStore(t)
+	ctx := context.Background()
+
+	_, err := store.Verify(ctx, OTPPurposePasswordReset, "no-such-user", "123456")
+	if err == nil {
+		t.Fatal("expected an error when no code has been stored")
+	}
+}
+
+func TestRedisOTPStoreVerifyLocksOutAfterMaxAttempts(t *testing.T) {
+	store := newTestOTPStore(t)
+	ctx := context.Background()
+
+	if err := store.Store(ctx, OTPPurposePasswordReset, "user-1", "123456", 0); err != nil {
+		t.Fatalf("Store returned error: %v", err)
+	}
+
+	for i := 0; i < otpMaxAttempts; i++ {
+		if _, err := store.Verify(ctx, OTPPurposePasswordReset, "user-1", "000000"); err != nil {
+			t.Fatalf("attempt %d: unexpected error before lockout: %v", i, err)
+		}
+	}
+
+	if _, err := store.Verify(ctx, OTPPurposePasswordReset, "user-1", "123456"); err == nil {
+		t.Fatal("expected the code to be locked out after otpMaxAttempts failed attempts")
+	}
+}
+
+func TestRedisOTPStoreClearRemovesCode(t *testing.T) {
+	store := newTestOTPStore(t)
+	ctx := context.Background()
+
+	if err := store.Store(ctx, OTPPurposePasswordReset, "user-1", "123456", 0); err != nil {
+		t.Fatalf("Store returned error: %v", err)
+	}
+	if err := store.Clear(ctx, OTPPurposePasswordReset, "user-1"); err != nil {
+		t.Fatalf("Clear returned error: %v", err)
+	}
+
+	if _, err := store.Verify(ctx, OTPPurposePasswordReset, "user-1", "123456"); err == nil {
+		t.Fatal("expected Verify to fail after Clear removed the code")
+	}
+}
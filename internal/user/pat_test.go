@@ -0,0 +1,102 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"had-service/internal/user/role"
+)
+
+func TestGeneratePATRoundTrip(t *testing.T) {
+	raw, hash, err := generatePAT()
+	if err != nil {
+		t.Fatalf("generatePAT returned error: %v", err)
+	}
+	if !strings.HasPrefix(raw, patPrefix) {
+		t.Fatalf("expected token to start with %q, got %q", patPrefix, raw)
+	}
+	if hash != hashPAT(raw) {
+		t.Fatal("hashPAT(raw) does not match the hash returned by generatePAT")
+	}
+}
+
+func TestGeneratePATIsUnpredictable(t *testing.T) {
+	rawA, _, err := generatePAT()
+	if err != nil {
+		t.Fatalf("generatePAT returned error: %v", err)
+	}
+	rawB, _, err := generatePAT()
+	if err != nil {
+		t.Fatalf("generatePAT returned error: %v", err)
+	}
+	if rawA == rawB {
+		t.Fatal("expected two generated tokens to differ")
+	}
+}
+
+func TestValidateAccessTokenReturnsScopedPATClaims(t *testing.T) {
+	repo := newFakeUserRepository()
+	user := &User{ID: "user-1", Username: "someone", Email: "someone@example.com", Role: role.User}
+	if err := repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	s := newTestAuthService(repo)
+	resp, err := s.CreateAccessToken(context.Background(), "user-1", CreateAccessTokenRequest{
+		Name:   "ci token",
+		Scopes: []string{"read:profile"},
+	})
+	if err != nil {
+		t.Fatalf("CreateAccessToken returned error: %v", err)
+	}
+
+	claims, err := s.validateAccessToken(context.Background(), resp.Token)
+	if err != nil {
+		t.Fatalf("validateAccessToken returned error: %v", err)
+	}
+	if !claims.IsPAT {
+		t.Fatal("expected claims built from a PAT to have IsPAT set")
+	}
+	if !claims.HasScope("read:profile") {
+		t.Fatal("expected claims to carry the token's own scopes")
+	}
+	if claims.HasScope(ScopeTokensManage) {
+		t.Fatal("expected claims not to carry a scope the token was never granted")
+	}
+	if !claims.HasRole(role.User) {
+		t.Fatal("expected claims to carry the owning user's role")
+	}
+}
+
+func TestValidateAccessTokenRejectsUnknownToken(t *testing.T) {
+	repo := newFakeUserRepository()
+	s := newTestAuthService(repo)
+
+	if _, err := s.validateAccessToken(context.Background(), patPrefix+"does-not-exist"); err == nil {
+		t.Fatal("expected an unknown access token to be rejected")
+	}
+}
+
+func TestValidateAccessTokenRejectsExpiredToken(t *testing.T) {
+	repo := newFakeUserRepository()
+	user := &User{ID: "user-1", Username: "someone", Email: "someone@example.com", Role: role.User}
+	if err := repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	s := newTestAuthService(repo)
+	expired := time.Now().Add(-time.Hour)
+	resp, err := s.CreateAccessToken(context.Background(), "user-1", CreateAccessTokenRequest{
+		Name:      "expired token",
+		ExpiresAt: &expired,
+	})
+	if err != nil {
+		t.Fatalf("CreateAccessToken returned error: %v", err)
+	}
+
+	if _, err := s.validateAccessToken(context.Background(), resp.Token); err == nil {
+		t.Fatal("expected an expired access token to be rejected")
+	}
+}
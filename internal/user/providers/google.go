@@ -0,0 +1,11 @@
+package providers
+
+// NewGoogleProvider builds a LoginProvider for "Sign in with Google",
+// using Google's well-known OAuth2/OIDC endpoints.
+func NewGoogleProvider(cfg OIDCConfig) *OIDCProvider {
+	return NewOIDCProvider("google", cfg, Endpoints{
+		AuthURL:     "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:    "https://oauth2.googleapis.com/token",
+		UserInfoURL: "https://openidconnect.googleapis.com/v1/userinfo",
+	})
+}
@@ -0,0 +1,60 @@
+// Package providers implements pluggable OAuth2/OIDC login providers
+// (Google, GitHub, generic OIDC) used by user.AuthService to authenticate
+// users via external identity providers.
+package providers
+
+import "context"
+
+// UserInfoFields is the normalized set of claims returned by a provider's
+// token/userinfo response. Providers disagree on key names for the same
+// claim (e.g. Google's "picture" vs GitHub's "avatar_url"), so callers
+// should prefer the typed getters below over indexing the map directly.
+type UserInfoFields map[string]any
+
+// GetString returns the string value for key, or "" if it is absent or
+// not a string.
+func (f UserInfoFields) GetString(key string) string {
+	if v, ok := f[key]; ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// GetBoolean returns the bool value for key, or false if it is absent or
+// not a bool.
+func (f UserInfoFields) GetBoolean(key string) bool {
+	if v, ok := f[key]; ok {
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	}
+	return false
+}
+
+// GetStringFromKeysOrEmpty tries each key in order and returns the first
+// non-empty string found, to smooth over claim-naming differences across
+// providers (e.g. "sub" vs "id", "picture" vs "avatar_url").
+func (f UserInfoFields) GetStringFromKeysOrEmpty(keys ...string) string {
+	for _, key := range keys {
+		if s := f.GetString(key); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// LoginProvider exchanges an OAuth2/OIDC authorization code for the
+// authenticated user's profile claims.
+type LoginProvider interface {
+	// Name identifies the provider, e.g. "google", "github", "oidc".
+	Name() string
+
+	// AuthURL builds the authorization redirect URL for the given state.
+	AuthURL(state string) string
+
+	// AttemptLogin exchanges the authorization code for an access token
+	// and resolves the user's profile claims.
+	AttemptLogin(ctx context.Context, code, state string) (UserInfoFields, error)
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 	"had-service/constants"
-	"log"
 	"time"
 )
 
@@ -17,6 +16,7 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id string) (*User, error)
 	GetByUsername(ctx context.Context, username string) (*User, error)
 	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetByProviderSubject(ctx context.Context, authType, providerSubject string) (*User, error)
 	Update(ctx context.Context, user *User) error
 	Delete(ctx context.Context, username string) error
 
@@ -24,8 +24,24 @@ type UserRepository interface {
 
 	// Session Management
 	CreateSession(ctx context.Context, session *UserSession) (*UserSession, error)
-	GetSession(ctx context.Context, userId string) (*User, error)
-	CleanSession(ctx context.Context, userId string) error
+	GetSession(ctx context.Context, userID, sessionID string) (*UserSession, error)
+	ListSessions(ctx context.Context, userID string) ([]UserSession, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	RevokeSessionFamily(ctx context.Context, userID, familyID string) error
+	LookupSessionFamily(ctx context.Context, sessionID string) (familyID, userID string, err error)
+	CleanSession(ctx context.Context, userID string) error
+
+	// MFA rate limiting
+	GetMFAFailureCount(ctx context.Context, userID string) (int64, error)
+	IncrementMFAFailure(ctx context.Context, userID string) (int64, error)
+	ResetMFAFailure(ctx context.Context, userID string) error
+
+	// Personal access tokens
+	CreateAccessToken(ctx context.Context, token *AccessToken) error
+	ListAccessTokens(ctx context.Context, userID string) ([]AccessToken, error)
+	RevokeAccessToken(ctx context.Context, userID, tokenID string) error
+	GetAccessTokenByHash(ctx context.Context, tokenHash string) (*AccessToken, error)
+	TouchAccessToken(ctx context.Context, tokenID string, usedAt time.Time) error
 }
 
 type UserPersistRepository struct {
@@ -81,6 +97,17 @@ func (r *UserPersistRepository) GetByEmail(ctx context.Context, email string) (*
 	return user, nil
 }
 
+func (r *UserPersistRepository) GetByProviderSubject(ctx context.Context, authType, providerSubject string) (*User, error) {
+	user := &User{}
+	if err := r.db.WithContext(ctx).First(user, "auth_type = ? AND provider_subject = ?", authType, providerSubject).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
 // Update User
 func (r *UserPersistRepository) Update(ctx context.Context, user *User) error {
 	return r.db.WithContext(ctx).Save(user).Error
@@ -91,91 +118,113 @@ func (r *UserPersistRepository) Delete(ctx context.Context, username string) err
 	return r.db.WithContext(ctx).Delete(&User{}, "username = ?", username).Error
 }
 
-// session management
-func (r *UserPersistRepository) CreateSession(ctx context.Context, session *UserSession) (*UserSession, error) {
-	// Set creation time if not provided
-	if session.CreatedAt.IsZero() {
-		session.CreatedAt = time.Now()
-	}
-	session.UpdatedAt = time.Now()
+// mfaBaseLockout is the TTL applied to the failure counter up to and
+// including mfaFailureThreshold failures; each failure beyond that sets a
+// window of 2^(count-mfaFailureThreshold) minutes instead, capped at
+// mfaMaxLockout.
+const (
+	mfaBaseLockout      = 15 * time.Minute
+	mfaFailureThreshold = 5
+	mfaMaxLockout       = time.Hour
+)
 
-	// Calculate TTL from expiration time
-	ttl := session.ExpiresAt.Sub(time.Now()) * 2
-	if ttl <= 0 {
-		return nil, errors.New("session already expired")
-	}
+// GetMFAFailureCount returns the user's current failure count without
+// recording an attempt, so callers can reject a request before it
+// consumes a real verification attempt.
+func (r *UserPersistRepository) GetMFAFailureCount(ctx context.Context, userID string) (int64, error) {
+	key := fmt.Sprintf(constants.MFAFailureRK, userID)
 
-	// Store each field separately in the hash
-	sessionKey := fmt.Sprintf(constants.UserSessionRK, session.UserID)
-	fields := map[string]interface{}{
-		"user_id":    session.UserID,
-		"user_agent": session.UserAgent,
-		"ip":         session.IP,
-		"expires_at": session.ExpiresAt.Format(time.RFC3339),
-		"created_at": session.CreatedAt.Format(time.RFC3339),
-		"updated_at": session.UpdatedAt.Format(time.RFC3339),
+	count, err := r.redis.Get(ctx, key).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read mfa failure counter: %w", err)
 	}
 
-	if err := r.redis.HSet(ctx, sessionKey, fields).Err(); err != nil {
-		return nil, fmt.Errorf("failed to store session in Redis: %w", err)
-	}
+	return count, nil
+}
+
+// IncrementMFAFailure bumps the per-user TOTP failure counter and returns
+// its new value, switching from mfaBaseLockout to an exponentially growing
+// lockout window once the count passes mfaFailureThreshold.
+func (r *UserPersistRepository) IncrementMFAFailure(ctx context.Context, userID string) (int64, error) {
+	key := fmt.Sprintf(constants.MFAFailureRK, userID)
 
-	// Set expiration on the hash
-	if err := r.redis.Expire(ctx, sessionKey, ttl).Err(); err != nil {
-		return nil, fmt.Errorf("failed to set session expiration: %w", err)
+	count, err := r.redis.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment mfa failure counter: %w", err)
 	}
 
-	// Use a single set for all active users
-	const activeUsersKey = constants.ActiveUsersRK
+	ttl := mfaBaseLockout
+	if count > mfaFailureThreshold {
+		backoff := time.Duration(1<<uint(count-mfaFailureThreshold)) * time.Minute
+		if backoff > mfaMaxLockout {
+			backoff = mfaMaxLockout
+		}
+		ttl = backoff
+	}
 
-	// Add this user to the active users set
-	if err := r.redis.SAdd(ctx, activeUsersKey, session.UserID).Err(); err != nil {
-		return nil, fmt.Errorf("failed to add user to active users set: %w", err)
+	if err := r.redis.Expire(ctx, key, ttl).Err(); err != nil {
+		return count, fmt.Errorf("failed to set mfa failure counter expiration: %w", err)
 	}
 
-	return session, nil
+	return count, nil
 }
 
-func (r *UserPersistRepository) GetSession(ctx context.Context, userId string) (*UserSession, error) {
-	sessionKey := fmt.Sprintf("session:%s", userId)
-
-	// Get all fields from the hash
-	sessionData, err := r.redis.HGetAll(ctx, sessionKey).Result()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get session data: %w", err)
+// ResetMFAFailure clears the failure counter after a successful TOTP check.
+func (r *UserPersistRepository) ResetMFAFailure(ctx context.Context, userID string) error {
+	key := fmt.Sprintf(constants.MFAFailureRK, userID)
+	if err := r.redis.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("failed to reset mfa failure counter: %w", err)
 	}
+	return nil
+}
 
-	// Check if session exists
-	if len(sessionData) == 0 {
-		return nil, fmt.Errorf("session not found for user %s", userId)
-	}
+// CreateAccessToken persists a new personal access token.
+func (r *UserPersistRepository) CreateAccessToken(ctx context.Context, token *AccessToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
 
-	// Reconstruct the session object from fields
-	session := &UserSession{
-		UserID:    userId,
-		UserAgent: sessionData["user_agent"],
-		IP:        sessionData["ip"],
+// ListAccessTokens returns every access token belonging to userID, most
+// recently created first.
+func (r *UserPersistRepository) ListAccessTokens(ctx context.Context, userID string) ([]AccessToken, error) {
+	var tokens []AccessToken
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error; err != nil {
+		return nil, err
 	}
+	return tokens, nil
+}
 
-	// Parse time fields
-	if expiresAt, err := time.Parse(time.RFC3339, sessionData["expires_at"]); err == nil {
-		session.ExpiresAt = expiresAt
-	}
-	if createdAt, err := time.Parse(time.RFC3339, sessionData["created_at"]); err == nil {
-		session.CreatedAt = createdAt
-	}
-	if updatedAt, err := time.Parse(time.RFC3339, sessionData["updated_at"]); err == nil {
-		session.UpdatedAt = updatedAt
-	}
+// RevokeAccessToken deletes tokenID, scoped to userID so a user can't
+// revoke another user's token.
+func (r *UserPersistRepository) RevokeAccessToken(ctx context.Context, userID, tokenID string) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND id = ?", userID, tokenID).
+		Delete(&AccessToken{}).Error
+}
 
-	// Check if session has expired
-	if time.Now().After(session.ExpiresAt) {
-		// Delete the expired session
-		if err := r.redis.Del(ctx, sessionKey).Err(); err != nil {
-			log.Printf("Failed to delete expired session: %v", err)
+// GetAccessTokenByHash looks up a personal access token by the SHA-256
+// hash of its plaintext, for ValidateToken to authenticate PAT requests.
+func (r *UserPersistRepository) GetAccessTokenByHash(ctx context.Context, tokenHash string) (*AccessToken, error) {
+	token := &AccessToken{}
+	if err := r.db.WithContext(ctx).First(token, "token_hash = ?", tokenHash).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("access token not found")
 		}
-		return nil, fmt.Errorf("session has expired")
+		return nil, err
 	}
+	return token, nil
+}
 
-	return session, nil
+// TouchAccessToken records that tokenID was just used to authenticate a
+// request.
+func (r *UserPersistRepository) TouchAccessToken(ctx context.Context, tokenID string, usedAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&AccessToken{}).
+		Where("id = ?", tokenID).
+		Update("last_used_at", usedAt).Error
 }
@@ -0,0 +1,170 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeUserRepository is an in-memory UserRepository double for tests that
+// exercise AuthServiceImpl without a real Postgres/Redis backend.
+type fakeUserRepository struct {
+	usersByID    map[string]*User
+	accessTokens map[string]*AccessToken // keyed by TokenHash
+}
+
+func newFakeUserRepository() *fakeUserRepository {
+	return &fakeUserRepository{
+		usersByID:    make(map[string]*User),
+		accessTokens: make(map[string]*AccessToken),
+	}
+}
+
+func (r *fakeUserRepository) Create(ctx context.Context, user *User) error {
+	if user.ID == "" {
+		user.ID = uuid.New().String()
+	}
+	r.usersByID[user.ID] = user
+	return nil
+}
+
+func (r *fakeUserRepository) GetByID(ctx context.Context, id string) (*User, error) {
+	user, ok := r.usersByID[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+func (r *fakeUserRepository) GetByUsername(ctx context.Context, username string) (*User, error) {
+	for _, user := range r.usersByID {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *fakeUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	for _, user := range r.usersByID {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *fakeUserRepository) GetByProviderSubject(ctx context.Context, authType, providerSubject string) (*User, error) {
+	for _, user := range r.usersByID {
+		if user.AuthType == authType && user.ProviderSubject != nil && *user.ProviderSubject == providerSubject {
+			return user, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *fakeUserRepository) Update(ctx context.Context, user *User) error {
+	if _, ok := r.usersByID[user.ID]; !ok {
+		return errors.New("user not found")
+	}
+	r.usersByID[user.ID] = user
+	return nil
+}
+
+func (r *fakeUserRepository) Delete(ctx context.Context, username string) error {
+	for id, user := range r.usersByID {
+		if user.Username == username {
+			delete(r.usersByID, id)
+			return nil
+		}
+	}
+	return errors.New("user not found")
+}
+
+func (r *fakeUserRepository) CreateSession(ctx context.Context, session *UserSession) (*UserSession, error) {
+	return session, nil
+}
+
+func (r *fakeUserRepository) GetSession(ctx context.Context, userID, sessionID string) (*UserSession, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *fakeUserRepository) ListSessions(ctx context.Context, userID string) ([]UserSession, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *fakeUserRepository) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	return nil
+}
+
+func (r *fakeUserRepository) RevokeSessionFamily(ctx context.Context, userID, familyID string) error {
+	return nil
+}
+
+func (r *fakeUserRepository) LookupSessionFamily(ctx context.Context, sessionID string) (familyID, userID string, err error) {
+	return "", "", errors.New("not implemented")
+}
+
+func (r *fakeUserRepository) CleanSession(ctx context.Context, userID string) error {
+	return nil
+}
+
+func (r *fakeUserRepository) GetMFAFailureCount(ctx context.Context, userID string) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeUserRepository) IncrementMFAFailure(ctx context.Context, userID string) (int64, error) {
+	return 1, nil
+}
+
+func (r *fakeUserRepository) ResetMFAFailure(ctx context.Context, userID string) error {
+	return nil
+}
+
+func (r *fakeUserRepository) CreateAccessToken(ctx context.Context, token *AccessToken) error {
+	if token.ID == "" {
+		token.ID = uuid.New().String()
+	}
+	r.accessTokens[token.TokenHash] = token
+	return nil
+}
+
+func (r *fakeUserRepository) ListAccessTokens(ctx context.Context, userID string) ([]AccessToken, error) {
+	var tokens []AccessToken
+	for _, token := range r.accessTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, *token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *fakeUserRepository) RevokeAccessToken(ctx context.Context, userID, tokenID string) error {
+	for hash, token := range r.accessTokens {
+		if token.UserID == userID && token.ID == tokenID {
+			delete(r.accessTokens, hash)
+			return nil
+		}
+	}
+	return errors.New("access token not found")
+}
+
+func (r *fakeUserRepository) GetAccessTokenByHash(ctx context.Context, tokenHash string) (*AccessToken, error) {
+	token, ok := r.accessTokens[tokenHash]
+	if !ok {
+		return nil, errors.New("access token not found")
+	}
+	return token, nil
+}
+
+func (r *fakeUserRepository) TouchAccessToken(ctx context.Context, tokenID string, usedAt time.Time) error {
+	for _, token := range r.accessTokens {
+		if token.ID == tokenID {
+			token.LastUsedAt = &usedAt
+			return nil
+		}
+	}
+	return errors.New("access token not found")
+}
@@ -0,0 +1,24 @@
+// Package role defines the RBAC roles assigned to users and checked by
+// the auth middleware.
+package role
+
+// Role is a coarse-grained permission tier assigned to a user. Finer
+// grained permissions are expressed as scopes rather than additional
+// roles.
+type Role string
+
+const (
+	User      Role = "user"
+	Moderator Role = "moderator"
+	Admin     Role = "admin"
+)
+
+// Valid reports whether r is one of the known roles.
+func (r Role) Valid() bool {
+	switch r {
+	case User, Moderator, Admin:
+		return true
+	default:
+		return false
+	}
+}
@@ -52,7 +52,6 @@ func (s *UserServiceImpl) Register(ctx context.Context, req CreateUserRequest) (
 		Username:       req.Username,
 		Email:          req.Email,
 		PasswordHash:   hashedPassword,
-		LuckyNumber:    req.LuckyNumber,
 		FirstName:      req.FirstName,
 		LastName:       req.LastName,
 		ProfilePicture: req.ProfilePicture,
@@ -0,0 +1,241 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"had-service/constants"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// CreateSession persists session as session:{userID}:{sessionID}, indexes
+// it under sessions:{userID}, and records its rotation family so a later
+// reuse of a retired refresh token can be traced back to it.
+func (r *UserPersistRepository) CreateSession(ctx context.Context, session *UserSession) (*UserSession, error) {
+	now := time.Now()
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = now
+	}
+	if session.LastSeenAt.IsZero() {
+		session.LastSeenAt = now
+	}
+	if session.FamilyID == "" {
+		session.FamilyID = session.ID
+	}
+	session.UpdatedAt = now
+
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return nil, errors.New("session already expired")
+	}
+
+	sessionKey := fmt.Sprintf(constants.UserSessionRK, session.UserID, session.ID)
+	indexKey := fmt.Sprintf(constants.UserSessionIndexRK, session.UserID)
+	familyKey := fmt.Sprintf(constants.SessionFamilyRK, session.FamilyID)
+	familyOwnerKey := fmt.Sprintf(constants.SessionFamilyOwnerRK, session.ID)
+
+	fields := map[string]interface{}{
+		"user_id":            session.UserID,
+		"family_id":          session.FamilyID,
+		"refresh_token_hash": session.RefreshTokenHash,
+		"user_agent":         session.UserAgent,
+		"ip":                 session.IP,
+		"expires_at":         session.ExpiresAt.Format(time.RFC3339),
+		"last_seen_at":       session.LastSeenAt.Format(time.RFC3339),
+		"created_at":         session.CreatedAt.Format(time.RFC3339),
+		"updated_at":         session.UpdatedAt.Format(time.RFC3339),
+	}
+
+	if err := r.redis.HSet(ctx, sessionKey, fields).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store session in redis: %w", err)
+	}
+	if err := r.redis.Expire(ctx, sessionKey, ttl).Err(); err != nil {
+		return nil, fmt.Errorf("failed to set session expiration: %w", err)
+	}
+	if err := r.redis.SAdd(ctx, indexKey, session.ID).Err(); err != nil {
+		return nil, fmt.Errorf("failed to index session for user: %w", err)
+	}
+	if err := r.redis.SAdd(ctx, familyKey, session.ID).Err(); err != nil {
+		return nil, fmt.Errorf("failed to index session family: %w", err)
+	}
+	if err := r.redis.Expire(ctx, familyKey, ttl).Err(); err != nil {
+		return nil, fmt.Errorf("failed to set session family expiration: %w", err)
+	}
+	if err := r.redis.Set(ctx, familyOwnerKey, session.UserID+":"+session.FamilyID, ttl).Err(); err != nil {
+		return nil, fmt.Errorf("failed to record session family owner: %w", err)
+	}
+	if err := r.redis.SAdd(ctx, constants.ActiveUsersRK, session.UserID).Err(); err != nil {
+		return nil, fmt.Errorf("failed to add user to active users set: %w", err)
+	}
+
+	return session, nil
+}
+
+// GetSession fetches a single session, cleaning it up if its TTL has
+// already lapsed (Redis may not have expired the hash key yet).
+func (r *UserPersistRepository) GetSession(ctx context.Context, userID, sessionID string) (*UserSession, error) {
+	sessionKey := fmt.Sprintf(constants.UserSessionRK, userID, sessionID)
+
+	data, err := r.redis.HGetAll(ctx, sessionKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session data: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("session not found")
+	}
+
+	session := &UserSession{
+		ID:               sessionID,
+		UserID:           userID,
+		FamilyID:         data["family_id"],
+		RefreshTokenHash: data["refresh_token_hash"],
+		UserAgent:        data["user_agent"],
+		IP:               data["ip"],
+	}
+	if t, err := time.Parse(time.RFC3339, data["expires_at"]); err == nil {
+		session.ExpiresAt = t
+	}
+	if t, err := time.Parse(time.RFC3339, data["last_seen_at"]); err == nil {
+		session.LastSeenAt = t
+	}
+	if t, err := time.Parse(time.RFC3339, data["created_at"]); err == nil {
+		session.CreatedAt = t
+	}
+	if t, err := time.Parse(time.RFC3339, data["updated_at"]); err == nil {
+		session.UpdatedAt = t
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		if err := r.RevokeSession(ctx, userID, sessionID); err != nil {
+			log.Printf("failed to clean up expired session: %v", err)
+		}
+		return nil, fmt.Errorf("session has expired")
+	}
+
+	return session, nil
+}
+
+// ListSessions returns every live session for userID, e.g. so the user
+// can see which devices are logged in. Sessions that turn out to have
+// already expired are dropped from the index as a side effect.
+func (r *UserPersistRepository) ListSessions(ctx context.Context, userID string) ([]UserSession, error) {
+	indexKey := fmt.Sprintf(constants.UserSessionIndexRK, userID)
+
+	sessionIDs, err := r.redis.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	sessions := make([]UserSession, 0, len(sessionIDs))
+	for _, id := range sessionIDs {
+		session, err := r.GetSession(ctx, userID, id)
+		if err != nil {
+			// Redis may already have expired the session's hash on its
+			// own; drop the index entry it left behind.
+			sessionKey := fmt.Sprintf(constants.UserSessionRK, userID, id)
+			if n, existsErr := r.redis.Exists(ctx, sessionKey).Result(); existsErr == nil && n == 0 {
+				if revokeErr := r.RevokeSession(ctx, userID, id); revokeErr != nil {
+					log.Printf("failed to clean up expired session: %v", revokeErr)
+				}
+			}
+			continue
+		}
+		sessions = append(sessions, *session)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession deletes a single session, dropping the user from
+// active_users once they have no sessions left.
+func (r *UserPersistRepository) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	sessionKey := fmt.Sprintf(constants.UserSessionRK, userID, sessionID)
+	indexKey := fmt.Sprintf(constants.UserSessionIndexRK, userID)
+
+	if err := r.redis.Del(ctx, sessionKey).Err(); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if err := r.redis.SRem(ctx, indexKey, sessionID).Err(); err != nil {
+		return fmt.Errorf("failed to remove session from index: %w", err)
+	}
+
+	remaining, err := r.redis.SCard(ctx, indexKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to count remaining sessions: %w", err)
+	}
+	if remaining == 0 {
+		if err := r.redis.SRem(ctx, constants.ActiveUsersRK, userID).Err(); err != nil {
+			return fmt.Errorf("failed to remove user from active users set: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RevokeSessionFamily revokes every session ever issued under familyID,
+// used when refresh-token reuse indicates the family may be compromised.
+func (r *UserPersistRepository) RevokeSessionFamily(ctx context.Context, userID, familyID string) error {
+	familyKey := fmt.Sprintf(constants.SessionFamilyRK, familyID)
+
+	sessionIDs, err := r.redis.SMembers(ctx, familyKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list session family members: %w", err)
+	}
+
+	for _, sessionID := range sessionIDs {
+		if err := r.RevokeSession(ctx, userID, sessionID); err != nil {
+			log.Printf("failed to revoke session %s while revoking family %s: %v", sessionID, familyID, err)
+		}
+	}
+
+	if err := r.redis.Del(ctx, familyKey).Err(); err != nil {
+		return fmt.Errorf("failed to delete session family: %w", err)
+	}
+
+	return nil
+}
+
+// LookupSessionFamily resolves a (possibly already-rotated-away) session
+// ID back to its owning user and rotation family, so refresh-token reuse
+// can be detected even after the original session has been replaced.
+func (r *UserPersistRepository) LookupSessionFamily(ctx context.Context, sessionID string) (familyID, userID string, err error) {
+	key := fmt.Sprintf(constants.SessionFamilyOwnerRK, sessionID)
+
+	value, err := r.redis.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", "", fmt.Errorf("unknown session")
+		}
+		return "", "", fmt.Errorf("failed to look up session family: %w", err)
+	}
+
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("corrupt session family record")
+	}
+
+	return parts[1], parts[0], nil
+}
+
+// CleanSession revokes every session belonging to userID, e.g. to rotate
+// sessions after a password reset.
+func (r *UserPersistRepository) CleanSession(ctx context.Context, userID string) error {
+	indexKey := fmt.Sprintf(constants.UserSessionIndexRK, userID)
+
+	sessionIDs, err := r.redis.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list sessions to clean: %w", err)
+	}
+
+	for _, sessionID := range sessionIDs {
+		if err := r.RevokeSession(ctx, userID, sessionID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}